@@ -0,0 +1,154 @@
+package comment
+
+import (
+	"go/token"
+	"testing"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"github.com/dave/dst/dstutil"
+)
+
+func parseTypeSpec(t *testing.T, src string) *dst.TypeSpec {
+	t.Helper()
+	file, err := decorator.Parse(src)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	genDecl, ok := file.Decls[0].(*dst.GenDecl)
+	if !ok || genDecl.Tok != token.TYPE {
+		t.Fatalf("expected the first declaration to be a type decl, got %#v", file.Decls[0])
+	}
+	return genDecl.Specs[0].(*dst.TypeSpec)
+}
+
+func TestDetectAPIType(t *testing.T) {
+	tests := []struct {
+		name             string
+		src              string
+		wantIsAPIType    bool
+		wantIsList       bool
+		wantListItemKind string
+	}{
+		{
+			name: "struct with embedded TypeMeta",
+			src: `package v1
+type Foo struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+}`,
+			wantIsAPIType: true,
+		},
+		{
+			name: "list struct with embedded ListMeta",
+			src: `package v1
+type FooList struct {
+	metav1.ListMeta ` + "`json:\",inline\"`" + `
+}`,
+			wantIsAPIType:    true,
+			wantIsList:       true,
+			wantListItemKind: "Foo",
+		},
+		{
+			name: "plain struct without TypeMeta or ListMeta",
+			src: `package v1
+type Foo struct {
+	Bar string ` + "`json:\"bar\"`" + `
+}`,
+			wantIsAPIType: false,
+		},
+		{
+			name: "non-struct type, e.g. an enum alias",
+			src: `package v1
+type Phase string`,
+			wantIsAPIType: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typeSpec := parseTypeSpec(t, tt.src)
+			isAPIType, isList, listItemKind := detectAPIType(typeSpec)
+			if isAPIType != tt.wantIsAPIType || isList != tt.wantIsList || listItemKind != tt.wantListItemKind {
+				t.Errorf("detectAPIType() = (%v, %v, %q), want (%v, %v, %q)",
+					isAPIType, isList, listItemKind, tt.wantIsAPIType, tt.wantIsList, tt.wantListItemKind)
+			}
+		})
+	}
+}
+
+func TestNonStructTypeWithExplicitLevelTagIsCommentedNotPanicked(t *testing.T) {
+	src := `package v1
+
+// +openshift:compatibility-gen:level=1
+type Phase string
+`
+	file, err := decorator.Parse(src)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	g := &compatibilityLevelCommentGenerator{group: "example.openshift.io", version: "v1"}
+	dstutil.Apply(file, nil, g.applyCompatibilityLevelComment())
+
+	if g.err != nil {
+		t.Fatalf("unexpected error: %v", g.err)
+	}
+	if !g.changed {
+		t.Fatal("expected a compatibility comment to be added to the tagged enum type")
+	}
+}
+
+func TestConstBlockWithExplicitLevelTagIsCommented(t *testing.T) {
+	src := `package v1
+
+type PodPhase string
+
+// +openshift:compatibility-gen:level=1
+const (
+	PodRunning PodPhase = "Running"
+	PodFailed  PodPhase = "Failed"
+)
+`
+	file, err := decorator.Parse(src)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	g := &compatibilityLevelCommentGenerator{group: "example.openshift.io", version: "v1", levels: map[GroupVersionKind]LevelInfo{}}
+	dstutil.Apply(file, nil, g.applyCompatibilityLevelComment())
+
+	if g.err != nil {
+		t.Fatalf("unexpected error: %v", g.err)
+	}
+	if !g.changed {
+		t.Fatal("expected a compatibility comment to be added to the tagged const block")
+	}
+	gvk := GroupVersionKind{Group: "example.openshift.io", Version: "v1", Kind: "PodPhase"}
+	if info, ok := g.levels[gvk]; !ok || info.Level != 1 {
+		t.Errorf("expected %v to be recorded at level 1, got %+v (found=%v)", gvk, info, ok)
+	}
+}
+
+func TestListWrapperInheritsItemLevel(t *testing.T) {
+	src := `package v1
+
+type FooList struct {
+	metav1.ListMeta ` + "`json:\",inline\"`" + `
+}
+`
+	file, err := decorator.Parse(src)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	g := &compatibilityLevelCommentGenerator{
+		group: "example.openshift.io", version: "v1",
+		levels:     map[GroupVersionKind]LevelInfo{},
+		itemLevels: map[string]LevelInfo{"Foo": {Level: 1}},
+	}
+	dstutil.Apply(file, nil, g.applyCompatibilityLevelComment())
+
+	if g.err != nil {
+		t.Fatalf("unexpected error: %v", g.err)
+	}
+	gvk := GroupVersionKind{Group: "example.openshift.io", Version: "v1", Kind: "FooList"}
+	if info, ok := g.levels[gvk]; !ok || info.Level != 1 {
+		t.Errorf("expected %v to inherit level 1 from its item type, got %+v (found=%v)", gvk, info, ok)
+	}
+}
@@ -0,0 +1,200 @@
+package comment
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dave/dst/decorator"
+	"github.com/dave/dst/dstutil"
+)
+
+func TestDeprecationCommentText(t *testing.T) {
+	tests := []struct {
+		name        string
+		kind        string
+		since       string
+		removedIn   string
+		replacement string
+		want        string
+	}{
+		{
+			name:      "without a replacement",
+			kind:      "Foo",
+			since:     "v1.0",
+			removedIn: "v1.3",
+			want:      "// Deprecated: Foo is deprecated in v1.0; it will be removed in v1.3.",
+		},
+		{
+			name:        "with a replacement",
+			kind:        "Foo",
+			since:       "v1.0",
+			removedIn:   "v1.3",
+			replacement: "example.openshift.io/v2.Foo",
+			want:        "// Deprecated: Foo is deprecated in v1.0; it will be removed in v1.3. Use example.openshift.io/v2.Foo instead.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deprecationCommentText(tt.kind, tt.since, tt.removedIn, tt.replacement); got != tt.want {
+				t.Errorf("deprecationCommentText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinReleasesNotice(t *testing.T) {
+	tests := []struct {
+		level int
+		want  int
+	}{
+		{level: 1, want: 3},
+		{level: 2, want: 2},
+		{level: 3, want: 1},
+		{level: 4, want: 0},
+	}
+	for _, tt := range tests {
+		if got := minReleasesNotice(tt.level); got != tt.want {
+			t.Errorf("minReleasesNotice(%d) = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestValidateDeprecationLifecycle(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     int
+		since     string
+		removedIn string
+		wantErr   string
+	}{
+		{
+			name:      "level 1 given exactly the minimum notice",
+			level:     1,
+			since:     "v1.0",
+			removedIn: "v1.3",
+		},
+		{
+			name:      "level 4 needs no notice at all",
+			level:     4,
+			since:     "v1.0",
+			removedIn: "v1.1",
+		},
+		{
+			name:      "removedIn before since",
+			level:     4,
+			since:     "v1.3",
+			removedIn: "v1.0",
+			wantErr:   "must name a version later than",
+		},
+		{
+			name:      "removedIn equal to since",
+			level:     4,
+			since:     "v1.0",
+			removedIn: "v1.0",
+			wantErr:   "must name a version later than",
+		},
+		{
+			name:      "level 1 given too little notice",
+			level:     1,
+			since:     "v1.0",
+			removedIn: "v1.1",
+			wantErr:   "must give at least 3 release(s) notice",
+		},
+		{
+			name:      "unparseable versions are left unchecked",
+			level:     1,
+			since:     "release-4.15",
+			removedIn: "release-4.16",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDeprecationLifecycle("Foo", tt.level, tt.since, tt.removedIn)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeprecatedTypeInsufficientNoticeFails(t *testing.T) {
+	src := `package v1
+
+// +openshift:compatibility-gen:level=1
+// +openshift:compatibility-gen:deprecated=v1.0
+// +openshift:compatibility-gen:removed-in=v1.1
+type Foo struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+}
+`
+	g := runGenerator(t, src)
+	if g.err == nil {
+		t.Fatal("expected an error for insufficient deprecation notice")
+	}
+	if !strings.Contains(g.err.Error(), "must give at least") {
+		t.Errorf("unexpected error: %v", g.err)
+	}
+}
+
+func TestDeprecatedTypeMissingRemovedInFails(t *testing.T) {
+	src := `package v1
+
+// +openshift:compatibility-gen:level=1
+// +openshift:compatibility-gen:deprecated=v1.0
+type Foo struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+}
+`
+	g := runGenerator(t, src)
+	if g.err == nil {
+		t.Fatal("expected an error when +deprecated is given without +removed-in")
+	}
+	if !strings.Contains(g.err.Error(), "must be specified together") {
+		t.Errorf("unexpected error: %v", g.err)
+	}
+}
+
+// runGeneratorSource behaves like runGenerator, but also renders the (possibly rewritten) file
+// back to source so the test can assert on the exact comment text that was inserted.
+func runGeneratorSource(t *testing.T, src string) (*compatibilityLevelCommentGenerator, string) {
+	t.Helper()
+	file, err := decorator.Parse(src)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	g := &compatibilityLevelCommentGenerator{group: "example.openshift.io", version: "v1", levels: map[GroupVersionKind]LevelInfo{}}
+	dstutil.Apply(file, nil, g.applyCompatibilityLevelComment())
+	var buf bytes.Buffer
+	if err := decorator.Fprint(&buf, file); err != nil {
+		t.Fatalf("failed to print rewritten source: %v", err)
+	}
+	return g, buf.String()
+}
+
+func TestDeprecationCommentTextIsInsertedVerbatim(t *testing.T) {
+	src := `package v1
+
+// +openshift:compatibility-gen:level=1
+// +openshift:compatibility-gen:deprecated=v1.0
+// +openshift:compatibility-gen:removed-in=v1.3
+// +openshift:compatibility-gen:replacement=example.openshift.io/v2.Foo
+type Foo struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+}
+`
+	g, out := runGeneratorSource(t, src)
+	if g.err != nil {
+		t.Fatalf("unexpected error: %v", g.err)
+	}
+	want := "// Deprecated: Foo is deprecated in v1.0; it will be removed in v1.3. Use example.openshift.io/v2.Foo instead."
+	if !strings.Contains(out, want) {
+		t.Errorf("rewritten source missing deprecation comment %q, got:\n%s", want, out)
+	}
+}
@@ -2,6 +2,7 @@ package comment
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"go/parser"
 	"go/token"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -21,37 +23,64 @@ import (
 )
 
 const (
-	baseTagName     = "openshift:compatibility-gen"
-	levelTagName    = baseTagName + ":level"
-	internalTagName = baseTagName + ":internal"
+	baseTagName        = "openshift:compatibility-gen"
+	levelTagName       = baseTagName + ":level"
+	internalTagName    = baseTagName + ":internal"
+	deprecatedTagName  = baseTagName + ":deprecated"
+	removedInTagName   = baseTagName + ":removed-in"
+	replacementTagName = baseTagName + ":replacement"
 )
 
 // GenerateCompatibilityComments add a compatibility level comment to instrumented types.
 func GenerateCompatibilityComments(inputPkgs []string) error {
+	_, err := GenerateCompatibilityCommentsWithLevels(inputPkgs)
+	return err
+}
+
+// GenerateCompatibilityCommentsWithLevels behaves like GenerateCompatibilityComments, but also
+// returns the compatibility level discovered for every API type it processed, keyed by the
+// GroupVersionKind the type belongs to. This is the same information that gets embedded into the
+// Go source as "// Compatibility level N" comments, made available to callers (CRD and OpenAPI
+// annotators, audit tooling) that need it in a machine-readable form.
+func GenerateCompatibilityCommentsWithLevels(inputPkgs []string) (map[GroupVersionKind]LevelInfo, error) {
+	return walkCompatibilityLevels(inputPkgs, false, nil)
+}
+
+// walkCompatibilityLevels parses every package in inputPkgs and runs the compatibility checker
+// over it, reporting the compatibility level discovered for every API type. When verifyOnly is
+// false (the GenerateCompatibilityComments path), stale or missing comments are rewritten in
+// place and the first validation problem halts the walk. When verifyOnly is true (the Verify and
+// Report path), nothing is written back, every validation problem is reported to onFinding instead
+// of halting, and the walk continues so a single pass can surface every problem in a package.
+func walkCompatibilityLevels(inputPkgs []string, verifyOnly bool, onFinding func(Finding)) (map[GroupVersionKind]LevelInfo, error) {
+	levels := map[GroupVersionKind]LevelInfo{}
 	for _, inputPkg := range inputPkgs {
 		output, err := exec.Command("go", "list", "-f", "{{ .Dir }}", inputPkg).Output()
 		if err != nil {
 			klog.Errorf(string(output))
-			return err
+			return nil, err
 		}
 		p := string(output)
 		p = strings.TrimSpace(p)
-		err = insertCompatibilityLevelComments(p)
-		if err != nil {
-			return err
+		if err := walkPackageDir(p, levels, verifyOnly, onFinding); err != nil {
+			return nil, err
 		}
 	}
-	return nil
+	return levels, nil
 }
 
-func insertCompatibilityLevelComments(path string) error {
+// walkPackageDir parses every file in the package rooted at path and runs the compatibility
+// checker over it. API packages live at .../<group>/<version>, which is all the context this has
+// for building a GroupVersionKind without depending on apimachinery's scheme registration.
+func walkPackageDir(path string, levels map[GroupVersionKind]LevelInfo, verifyOnly bool, onFinding func(Finding)) error {
 	pkgs, err := decorator.ParseDir(token.NewFileSet(), path, onlyTypesFiles, parser.ParseComments)
 	if err != nil {
 		return err
 	}
+	group := filepath.Base(filepath.Dir(path))
+	version := filepath.Base(path)
 	for _, pkg := range pkgs {
-		err = processPackage(pkg)
-		if err != nil {
+		if err := processPackage(pkg, group, version, levels, verifyOnly, onFinding); err != nil {
 			return err
 		}
 	}
@@ -70,22 +99,30 @@ func onlyTypesFiles(info os.FileInfo) bool {
 	return true
 }
 
-// processPackage processes all the files in a package
-func processPackage(pkg *dst.Package) error {
+// processPackage processes all the files in a package. When verifyOnly is true, no file is ever
+// written back and every validation problem is reported to onFinding instead of halting the walk.
+func processPackage(pkg *dst.Package, group, version string, levels map[GroupVersionKind]LevelInfo, verifyOnly bool, onFinding func(Finding)) error {
+	itemLevels := collectItemLevels(pkg)
 	for fileName, file := range pkg.Files {
-		fileChanged, err := processFile(file)
-		if err != nil {
-			return err
+		g := compatibilityLevelCommentGenerator{group: group, version: version, levels: levels, itemLevels: itemLevels, verifyOnly: verifyOnly}
+		dstutil.Apply(file, nil, g.applyCompatibilityLevelComment())
+		for _, finding := range g.findings {
+			if onFinding != nil {
+				onFinding(finding)
+			}
 		}
-		if !fileChanged {
+		if g.err != nil {
+			return g.err
+		}
+		if verifyOnly || !g.changed {
 			continue
 		}
 		removeIgnoreAutogeneratedBuildTag(file)
 		var buf bytes.Buffer
-		if err = decorator.Fprint(&buf, file); err != nil {
+		if err := decorator.Fprint(&buf, file); err != nil {
 			return err
 		}
-		if err = ioutil.WriteFile(fileName, buf.Bytes(), 0777); err != nil {
+		if err := ioutil.WriteFile(fileName, buf.Bytes(), 0777); err != nil {
 			return err
 		}
 	}
@@ -109,144 +146,461 @@ func removeIgnoreAutogeneratedBuildTag(file *dst.File) {
 	}
 }
 
-// processFile adds compatibility level comments to a file
-func processFile(f *dst.File) (bool, error) {
-	g := compatibilityLevelCommentGenerator{}
-	dstutil.Apply(f, nil, g.applyCompatibilityLevelComment())
-	return g.changed, g.err
-}
-
 // compatibilityLevelCommentGenerator provides an ApplyFunc for dst.Apply() and knows if
 // the ApplyFunc actually changed the source code.
 type compatibilityLevelCommentGenerator struct {
 	changed bool
 	err     error
+
+	// group and version identify the package being processed, and levels accumulates the
+	// compatibility level discovered for each API type so it can be consumed outside of the
+	// Go source comments (see GenerateCompatibilityCommentsWithLevels).
+	group   string
+	version string
+	levels  map[GroupVersionKind]LevelInfo
+
+	// itemLevels holds the compatibility level of every non-list API type discovered elsewhere
+	// in the package, keyed by Kind. It lets a "*List" struct inherit the level of the item type
+	// it wraps; see detectAPIType and collectItemLevels.
+	itemLevels map[string]LevelInfo
+
+	// verifyOnly, when set, switches every validation failure from halting with err (the
+	// behavior GenerateCompatibilityComments needs) to being recorded in findings instead, so
+	// Verify can report every problem in a package in one pass. It also stops comments from
+	// being rewritten in place; a stale comment is reported as a finding rather than fixed.
+	verifyOnly bool
+	findings   []Finding
+}
+
+// fail records a validation problem found while checking apiTypeName (and, if non-empty, one of
+// its fields). In normal mode the first such problem is kept in g.err, which processPackage checks
+// once the whole file has been walked: that file is then not written and the rest of the package is
+// not processed (files already written earlier in the walk stay written); in verify-only mode the problem is
+// appended to findings instead, so a single pass can surface every problem in a package rather than
+// stopping at the first one. Its bool return is for the caller's own early-exit bookkeeping only —
+// it must never be threaded back out as an ApplyFunc's return value: dst's post functions treat
+// false as "abort the rest of the traversal", not "skip this subtree", so doing that would stop
+// verify-only callers from ever seeing the declarations after the first problem.
+func (g *compatibilityLevelCommentGenerator) fail(apiTypeName, field string, level int, internal bool, format string, args ...interface{}) bool {
+	msg := fmt.Sprintf(format, args...)
+	if !g.verifyOnly {
+		if g.err == nil {
+			g.err = errors.New(msg)
+		}
+		return false
+	}
+	g.findings = append(g.findings, Finding{
+		Package:  fmt.Sprintf("%s/%s", g.group, g.version),
+		Kind:     apiTypeName,
+		Field:    field,
+		Level:    level,
+		Internal: internal,
+		Message:  msg,
+	})
+	return false
 }
 
 // applyCompatibilityLevelComment returns an ApplyFunc that inserts compatibility level comments.
+// The returned func always reports "continue" to dstutil.Apply: a post ApplyFunc returning false
+// aborts the entire traversal rather than just skipping the failed node's subtree, so a single bad
+// declaration must never be allowed to stop the rest of the file from being checked — particularly
+// in verify-only mode, where every declaration needs to be visited to collect every finding.
 func (g *compatibilityLevelCommentGenerator) applyCompatibilityLevelComment() dstutil.ApplyFunc {
 	return func(c *dstutil.Cursor) bool {
+		g.checkDecl(c)
+		return true
+	}
+}
 
-		genDecl, ok := c.Node().(*dst.GenDecl)
-		if !ok {
+// checkDecl inspects the declaration at c and, if it's a compatibility-tagged API type, validates
+// and comments it. Its bool return communicates success only to its own internal early-exits (e.g.
+// the field-level pass below); it carries no meaning for dstutil's traversal and must not be
+// returned from the ApplyFunc itself.
+func (g *compatibilityLevelCommentGenerator) checkDecl(c *dstutil.Cursor) bool {
+	genDecl, ok := c.Node().(*dst.GenDecl)
+	if !ok {
+		return true
+	}
+	// we have a generic declaration
+
+	if genDecl.Tok == token.CONST {
+		return g.applyConstBlockComment(genDecl)
+	}
+	if genDecl.Tok != token.TYPE {
+		return true
+	}
+	// we have a type declaration
+
+	typeSpec := genDecl.Specs[0].(*dst.TypeSpec)
+	apiTypeName := typeSpec.Name.Name
+
+	isAPIType, isList, listItemKind := detectAPIType(typeSpec)
+	if !isAPIType {
+		// not a struct with an embedded TypeMeta/ListMeta, but it might still be an
+		// explicitly-tagged enum, alias, or other non-struct type
+		_, hasLevel := extractCompatibilityLevel(genDecl, apiTypeName)
+		if !hasLevel && !extractIsInternal(genDecl, apiTypeName) {
 			return true
 		}
-		// we have a generic declaration
+	}
+	// we have an API Type
+	klog.V(5).Infof("API type found: %v", apiTypeName)
+
+	klog.V(5).Infof("Checking %v...", apiTypeName)
+	klog.V(5).Infof("  Before  : %v", genDecl.Decorations().Before.String())
+	klog.V(5).Infof("  After   : %v", genDecl.Decorations().After.String())
+	klog.V(5).Infof("  Start   : %#v", genDecl.Decorations().Start.All())
+	klog.V(5).Infof("  End     : %#v", genDecl.Decorations().End.All())
+
+	internal := extractIsInternal(genDecl, apiTypeName)
+	klog.V(5).Infof("  Internal: %v", internal)
+
+	level, ok := extractCompatibilityLevel(genDecl, apiTypeName)
+	if !ok && isList {
+		// a List wrapper inherits the level of the item type it wraps, if one was found
+		// elsewhere in the package
+		if itemInfo, found := g.itemLevels[listItemKind]; found {
+			level, internal, ok = itemInfo.Level, itemInfo.Internal, true
+		}
+	}
+	if !internal && !ok {
+		return g.fail(apiTypeName, "", level, internal, "%s: level or internal must be specified", apiTypeName)
+	}
+	if !ok {
+		level = 4 // default level for internal types
+	}
+	klog.V(5).Infof("  Level   : %v", level)
 
-		if genDecl.Tok != token.TYPE {
-			return true
+	ga := versionIsGenerallyAvailable(c)
+	beta := versionIsPrerelease(c)
+	alpha := versionIsExperimental(c)
+
+	klog.V(5).Infof("  GA/A/B  : %v/%v/%v", ga, beta, alpha)
+
+	switch {
+	case internal && level != 4:
+		return g.fail(apiTypeName, "", level, internal, "%s: APIs that are internal are only allowed to offer level 4 compatibility: long term support cannot be offered for the %s API", apiTypeName, apiTypeName)
+	case internal:
+	case !(ga || alpha || beta):
+		return g.fail(apiTypeName, "", level, internal, "%s: APIs whose versions do not conform to kube apiVersion format cannot be exposed: the %s API must be tagged with +%s", apiTypeName, apiTypeName, internalTagName)
+	case ga && level != 1:
+		return g.fail(apiTypeName, "", level, internal, "%s: generally available APIs must be supported for a minimum of 12 months", apiTypeName)
+	case beta && level == 1:
+		return g.fail(apiTypeName, "", level, internal, "%s: pre-release (beta) APIs must offer level 2 compatibility: the %s API should be versioned as generally available if you with to offer level 1 compatibility", apiTypeName, apiTypeName)
+	case beta && level == 4:
+		return g.fail(apiTypeName, "", level, internal, "%s: pre-release (beta) APIs must offer level 2 compatibility: the %s API should be versioned as experimental (alpha) if you wish to offer level 4 compatibility", apiTypeName, apiTypeName)
+	case alpha && level != 4:
+		return g.fail(apiTypeName, "", level, internal, "%s: experimental (alpha) APIs are only allowed to offer level 4 compatibility: long term support cannot be offered for the %s API", apiTypeName, apiTypeName)
+	}
+
+	// we have a compatibility level tag
+
+	since, deprecated := extractDeprecated(genDecl)
+	removedIn, hasRemovedIn := extractRemovedIn(genDecl)
+	replacement, _ := extractReplacement(genDecl)
+	switch {
+	case deprecated != hasRemovedIn:
+		return g.fail(apiTypeName, "", level, internal, "%s: +%s and +%s must be specified together", apiTypeName, deprecatedTagName, removedInTagName)
+	case deprecated:
+		if err := validateDeprecationLifecycle(apiTypeName, level, since, removedIn); err != nil {
+			return g.fail(apiTypeName, "", level, internal, "%v", err)
+		}
+	}
+
+	if g.levels != nil {
+		gvk := GroupVersionKind{Group: g.group, Version: g.version, Kind: apiTypeName}
+		g.levels[gvk] = LevelInfo{Level: level, Internal: internal, Deprecated: deprecated}
+	}
+
+	// add/edit comments as needed
+	if g.verifyOnly {
+		if stale, want := compatibilityCommentIsStale(genDecl, level); stale {
+			g.findings = append(g.findings, Finding{
+				Package: fmt.Sprintf("%s/%s", g.group, g.version),
+				Kind:    apiTypeName, Level: level, Internal: internal,
+				Message: fmt.Sprintf("%s: compatibility comment missing or out of date, want %q", apiTypeName, want),
+			})
 		}
-		// we have a type declaration
+	} else if ensureCompatibilityLevelComment(genDecl, level) {
+		g.changed = true
+	}
 
-		typeSpec := genDecl.Specs[0].(*dst.TypeSpec)
-		structType, ok := typeSpec.Type.(*dst.StructType)
+	if deprecated {
+		text := deprecationCommentText(apiTypeName, since, removedIn, replacement)
+		if g.verifyOnly {
+			if stale, want := deprecationCommentIsStale(genDecl, text); stale {
+				g.findings = append(g.findings, Finding{
+					Package: fmt.Sprintf("%s/%s", g.group, g.version),
+					Kind:    apiTypeName, Level: level, Internal: internal,
+					Message: fmt.Sprintf("%s: deprecation comment missing or out of date, want %q", apiTypeName, want),
+				})
+			}
+		} else if ensureDeprecationComment(genDecl, text) {
+			g.changed = true
+		}
+	}
+
+	if structType, ok := typeSpec.Type.(*dst.StructType); ok {
+		if !g.applyFieldCompatibilityLevels(structType, apiTypeName, level) {
+			return false
+		}
+	}
+
+	// continue to process nodes
+	return true
+}
+
+// detectAPIType reports whether typeSpec looks like an API type: a struct with an embedded
+// TypeMeta, or a "*List" struct with an embedded ListMeta. For the latter, it also returns the
+// Kind name of the item type the list wraps (e.g. "Pod" for "PodList"), so its level can be
+// looked up in itemLevels.
+func detectAPIType(typeSpec *dst.TypeSpec) (isAPIType, isList bool, listItemKind string) {
+	structType, ok := typeSpec.Type.(*dst.StructType)
+	if !ok {
+		return false, false, ""
+	}
+	for _, field := range structType.Fields.List {
+		if len(field.Names) != 0 {
+			continue
+		}
+		selectorExpr, ok := field.Type.(*dst.SelectorExpr)
 		if !ok {
-			return true
+			continue
 		}
-		// we have a struct type declaration
+		switch selectorExpr.Sel.Name {
+		case "TypeMeta":
+			isAPIType = true
+		case "ListMeta":
+			if itemKind := strings.TrimSuffix(typeSpec.Name.Name, "List"); itemKind != typeSpec.Name.Name {
+				isAPIType = true
+				isList = true
+				listItemKind = itemKind
+			}
+		}
+	}
+	return isAPIType, isList, listItemKind
+}
 
-		var isAPIType bool
-		for _, field := range structType.Fields.List {
-			if len(field.Names) != 0 {
+// collectItemLevels does a read-only pre-pass over every file in pkg, gathering the
+// compatibility level of every explicitly-tagged, non-list API type. It lets List wrappers
+// (processed in the same package, but not necessarily the same file) inherit the level of the
+// item type they wrap without requiring the files to be processed in a particular order.
+func collectItemLevels(pkg *dst.Package) map[string]LevelInfo {
+	items := map[string]LevelInfo{}
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*dst.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
 				continue
 			}
-			selectorExpr, ok := field.Type.(*dst.SelectorExpr)
-			if !ok {
+			typeSpec, ok := genDecl.Specs[0].(*dst.TypeSpec)
+			if !ok || strings.HasSuffix(typeSpec.Name.Name, "List") {
 				continue
 			}
-			if selectorExpr.Sel.Name != "TypeMeta" {
-				continue
+			internal := extractIsInternal(genDecl, typeSpec.Name.Name)
+			level, ok := extractCompatibilityLevel(genDecl, typeSpec.Name.Name)
+			if !ok {
+				if !internal {
+					continue
+				}
+				level = 4
 			}
-			isAPIType = true
-			break
+			items[typeSpec.Name.Name] = LevelInfo{Level: level, Internal: internal}
 		}
-		if !isAPIType {
-			return true
-		}
-		apiTypeName := typeSpec.Name.Name
-		// we have an API Type
-		klog.V(5).Infof("API type found: %v", apiTypeName)
+	}
+	return items
+}
 
-		klog.V(5).Infof("Checking %v...", apiTypeName)
-		klog.V(5).Infof("  Before  : %v", genDecl.Decorations().Before.String())
-		klog.V(5).Infof("  After   : %v", genDecl.Decorations().After.String())
-		klog.V(5).Infof("  Start   : %#v", genDecl.Decorations().Start.All())
-		klog.V(5).Infof("  End     : %#v", genDecl.Decorations().End.All())
+// applyConstBlockComment handles a grouped `const (...)` declaration tagged with
+// +openshift:compatibility-gen:level, applying a single compatibility comment to the whole
+// block. This covers enum-style API types, e.g. the named values of a `type Phase string`.
+func (g *compatibilityLevelCommentGenerator) applyConstBlockComment(genDecl *dst.GenDecl) bool {
+	blockName := constBlockName(genDecl)
 
-		internal := extractIsInternal(genDecl)
-		klog.V(5).Infof("  Internal: %v", internal)
+	internal := extractIsInternal(genDecl, blockName)
+	level, ok := extractCompatibilityLevel(genDecl, blockName)
+	if !ok && !internal {
+		return true // not a tagged const block, leave it alone
+	}
+	if !ok {
+		level = 4
+	}
 
-		level, ok := extractCompatibilityLevel(genDecl)
-		if !internal && !ok {
-			g.err = fmt.Errorf("%s: level or internal must be specified", apiTypeName)
-			return false
+	if g.levels != nil && blockName != "" {
+		gvk := GroupVersionKind{Group: g.group, Version: g.version, Kind: blockName}
+		g.levels[gvk] = LevelInfo{Level: level, Internal: internal}
+	}
+
+	if g.verifyOnly {
+		if stale, want := compatibilityCommentIsStale(genDecl, level); stale {
+			g.findings = append(g.findings, Finding{
+				Package: fmt.Sprintf("%s/%s", g.group, g.version),
+				Kind:    blockName, Level: level, Internal: internal,
+				Message: fmt.Sprintf("%s: compatibility comment missing or out of date, want %q", blockName, want),
+			})
+		}
+		return true
+	}
+	if ensureCompatibilityLevelComment(genDecl, level) {
+		g.changed = true
+	}
+	return true
+}
+
+// constBlockName returns the shared type name of a grouped const declaration's values (e.g.
+// "PodPhase" for a block of PodPhase-typed constants), or "" if the values aren't all typed the
+// same way.
+func constBlockName(genDecl *dst.GenDecl) string {
+	var name string
+	for _, spec := range genDecl.Specs {
+		valueSpec, ok := spec.(*dst.ValueSpec)
+		if !ok || valueSpec.Type == nil {
+			continue
 		}
+		ident, ok := valueSpec.Type.(*dst.Ident)
 		if !ok {
-			level = 4 // default level for internal types
+			continue
 		}
-		klog.V(5).Infof("  Level   : %v", level)
+		switch {
+		case name == "":
+			name = ident.Name
+		case name != ident.Name:
+			return ""
+		}
+	}
+	return name
+}
 
-		ga := versionIsGenerallyAvailable(c)
-		beta := versionIsPrerelease(c)
-		alpha := versionIsExperimental(c)
+// applyFieldCompatibilityLevels looks for fields of an API type that carry their own
+// +openshift:compatibility-gen:level or +openshift:compatibility-gen:internal tag and adds a
+// "// Compatibility level N" comment to each, so that an otherwise-stable type can carry an
+// experimental field (e.g. a level 4 field on a level 1 type). Fields without either tag are
+// left untouched.
+func (g *compatibilityLevelCommentGenerator) applyFieldCompatibilityLevels(structType *dst.StructType, apiTypeName string, typeLevel int) bool {
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			// anonymous/embedded field, e.g. the TypeMeta/ListMeta this type embeds
+			continue
+		}
+		fieldName := field.Names[0].Name
+		qualifiedName := fmt.Sprintf("%s.%s", apiTypeName, fieldName)
 
-		klog.V(5).Infof("  GA/A/B  : %v/%v/%v", ga, beta, alpha)
+		fieldInternal := extractIsInternal(field, qualifiedName)
+		fieldLevel, ok := extractCompatibilityLevel(field, qualifiedName)
+		if !fieldInternal && !ok {
+			continue
+		}
+		if !ok {
+			fieldLevel = 4 // default level for internal fields
+		}
 
+		if fieldLevel < typeLevel {
+			g.fail(apiTypeName, fieldName, fieldLevel, fieldInternal, "%s: a field cannot offer a stronger compatibility guarantee than its type: %s is level %d but %s is tagged level %d", qualifiedName, apiTypeName, typeLevel, qualifiedName, fieldLevel)
+			if !g.verifyOnly {
+				return false
+			}
+			continue
+		}
+
+		since, deprecated := extractDeprecated(field)
+		removedIn, hasRemovedIn := extractRemovedIn(field)
+		replacement, _ := extractReplacement(field)
 		switch {
-		case internal && level != 4:
-			g.err = fmt.Errorf("%s: APIs that are not internal are only allowed to offer level 4 compatibility: long term support cannot be offered for the %s API", apiTypeName, apiTypeName)
-			return false
-		case internal:
-		case !(ga || alpha || beta):
-			g.err = fmt.Errorf("%s: APIs whose versions do not conform to kube apiVersion format cannot be exposed: the %s API must be tagged with +%s", apiTypeName, apiTypeName, internalTagName)
-			return false
-		case ga && level != 1:
-			g.err = fmt.Errorf("%s: generally available APIs must be supported for a minimum of 12 months", apiTypeName)
-			return false
-		case beta && level == 1:
-			g.err = fmt.Errorf("%s: pre-release (beta) APIs must offer level 2 compatibility: the %s API should be versioned as generally available if you with to offer level 1 compatibility", apiTypeName, apiTypeName)
-			return false
-		case beta && level == 4:
-			g.err = fmt.Errorf("%s: pre-release (beta) APIs must offer level 2 compatibility: the %s API should be versioned as experimental (alpha) if you wish to offer level 4 compatibility", apiTypeName, apiTypeName)
-			return false
-		case alpha && level != 4:
-			g.err = fmt.Errorf("%s: experimental (alpha) APIs are only allowed to offer level 4 compatibility: long term support cannot be offered for the %s API", apiTypeName, apiTypeName)
-			return false
+		case deprecated != hasRemovedIn:
+			g.fail(apiTypeName, fieldName, fieldLevel, fieldInternal, "%s: +%s and +%s must be specified together", qualifiedName, deprecatedTagName, removedInTagName)
+			if !g.verifyOnly {
+				return false
+			}
+			continue
+		case deprecated:
+			if err := validateDeprecationLifecycle(qualifiedName, fieldLevel, since, removedIn); err != nil {
+				g.fail(apiTypeName, fieldName, fieldLevel, fieldInternal, "%v", err)
+				if !g.verifyOnly {
+					return false
+				}
+				continue
+			}
 		}
 
-		// we have a compatibility level tag
+		if g.verifyOnly {
+			if stale, want := compatibilityCommentIsStale(field, fieldLevel); stale {
+				g.findings = append(g.findings, Finding{
+					Package: fmt.Sprintf("%s/%s", g.group, g.version),
+					Kind:    apiTypeName, Field: fieldName, Level: fieldLevel, Internal: fieldInternal,
+					Message: fmt.Sprintf("%s: compatibility comment missing or out of date, want %q", qualifiedName, want),
+				})
+			}
+			if deprecated {
+				text := deprecationCommentText(qualifiedName, since, removedIn, replacement)
+				if stale, want := deprecationCommentIsStale(field, text); stale {
+					g.findings = append(g.findings, Finding{
+						Package: fmt.Sprintf("%s/%s", g.group, g.version),
+						Kind:    apiTypeName, Field: fieldName, Level: fieldLevel, Internal: fieldInternal,
+						Message: fmt.Sprintf("%s: deprecation comment missing or out of date, want %q", qualifiedName, want),
+					})
+				}
+			}
+			continue
+		}
 
-		// add/edit comments as needed
-		changed := ensureCompatibilityLevelComment(genDecl, level)
-		if changed {
+		if ensureCompatibilityLevelComment(field, fieldLevel) {
+			g.changed = true
+		}
+		if deprecated && ensureDeprecationComment(field, deprecationCommentText(qualifiedName, since, removedIn, replacement)) {
 			g.changed = true
 		}
+	}
+	return true
+}
 
-		// continue to process nodes
-		return true
+// compatibilityCommentIsStale reports whether node's existing "// Compatibility level" comment
+// (if any) differs from what level would produce, without mutating node. It is the read-only
+// counterpart to ensureCompatibilityLevelComment used by Verify.
+func compatibilityCommentIsStale(node dst.Node, level int) (bool, string) {
+	return commentLineIsStale(node, "// Compatibility level ", fmt.Sprintf("// Compatibility level %d: %s", level, commentForLevel(level)))
+}
+
+// deprecationCommentIsStale reports whether node's existing "// Deprecated: " comment (if any)
+// differs from text, without mutating node. It is the read-only counterpart to
+// ensureDeprecationComment used by Verify.
+func deprecationCommentIsStale(node dst.Node, text string) (bool, string) {
+	return commentLineIsStale(node, "// Deprecated: ", text)
+}
+
+// commentLineIsStale reports whether node already carries a comment starting with prefix that
+// reads exactly full, without mutating node.
+func commentLineIsStale(node dst.Node, prefix, full string) (bool, string) {
+	for _, existing := range node.Decorations().Start.All() {
+		switch {
+		case existing == full:
+			return false, full
+		case strings.HasPrefix(existing, prefix):
+			return true, full
+		}
 	}
+	return true, full
 }
 
-func extractCompatibilityLevel(spec *dst.GenDecl) (int, bool) {
-	tags := types.ExtractCommentTags("// +", spec.Decorations().Start.All())
+func extractCompatibilityLevel(node dst.Node, name string) (int, bool) {
+	tags := types.ExtractCommentTags("// +", node.Decorations().Start.All())
 	value, ok := tags[levelTagName]
 	if !ok {
 		return 0, false
 	}
 	level, err := strconv.Atoi(value[0])
 	if err != nil {
-		klog.Errorf("%s: unable to parse value of %s tag: %v", typeName(spec), levelTagName, err)
+		klog.Errorf("%s: unable to parse value of %s tag: %v", name, levelTagName, err)
 	}
 	switch level {
 	case 1, 2, 3, 4:
 	default:
-		klog.Errorf("%s: invalid value of %s tag: %v", typeName(spec), levelTagName, level)
+		klog.Errorf("%s: invalid value of %s tag: %v", name, levelTagName, level)
 	}
 	return level, true
 }
 
-func extractIsInternal(spec *dst.GenDecl) bool {
-	tags := types.ExtractCommentTags("// +", spec.Decorations().Start.All())
+func extractIsInternal(node dst.Node, name string) bool {
+	tags := types.ExtractCommentTags("// +", node.Decorations().Start.All())
 	value, ok := tags[internalTagName]
 	if !ok {
 		return false
@@ -256,13 +610,36 @@ func extractIsInternal(spec *dst.GenDecl) bool {
 	}
 	internal, err := strconv.ParseBool(value[0])
 	if err != nil {
-		klog.Fatalf("%s: error parsing %s tag: %v", typeName(spec), err)
+		klog.Fatalf("%s: error parsing %s tag: %v", name, internalTagName, err)
 	}
 	return internal
 }
 
-func typeName(spec *dst.GenDecl) string {
-	return spec.Specs[0].(*dst.TypeSpec).Name.String()
+// extractDeprecated returns the version given to a +openshift:compatibility-gen:deprecated tag,
+// if present.
+func extractDeprecated(node dst.Node) (string, bool) {
+	return extractSingleValueTag(node, deprecatedTagName)
+}
+
+// extractRemovedIn returns the version given to a +openshift:compatibility-gen:removed-in tag,
+// if present.
+func extractRemovedIn(node dst.Node) (string, bool) {
+	return extractSingleValueTag(node, removedInTagName)
+}
+
+// extractReplacement returns the GVK given to a +openshift:compatibility-gen:replacement tag, if
+// present.
+func extractReplacement(node dst.Node) (string, bool) {
+	return extractSingleValueTag(node, replacementTagName)
+}
+
+func extractSingleValueTag(node dst.Node, tagName string) (string, bool) {
+	tags := types.ExtractCommentTags("// +", node.Decorations().Start.All())
+	value, ok := tags[tagName]
+	if !ok || len(value) == 0 {
+		return "", false
+	}
+	return value[0], true
 }
 
 func versionIsGenerallyAvailable(c *dstutil.Cursor) bool {
@@ -277,26 +654,40 @@ func versionIsExperimental(c *dstutil.Cursor) bool {
 	return regexp.MustCompile(`^v\d*alpha\d*$`).MatchString(path.Base((c.Parent().(*dst.File)).Name.String()))
 }
 
-// ensureCompatibilityLevelComment either replaces a stale compatibility level comment, or adds a new one.
-func ensureCompatibilityLevelComment(genDecl *dst.GenDecl, level int) bool {
-	// copy of existing comments we can manipulate
-	comments := append([]string{}, genDecl.Decorations().Start.All()...)
+// ensureCompatibilityLevelComment either replaces a stale compatibility level comment, or adds a
+// new one. It operates on any decorated node (a *dst.GenDecl for a type, or a *dst.Field for a
+// field-level override).
+func ensureCompatibilityLevelComment(node dst.Node, level int) bool {
+	return ensureCommentLine(node, "// Compatibility level ", fmt.Sprintf("// Compatibility level %d: %s", level, commentForLevel(level)))
+}
 
-	newComment := fmt.Sprintf("// Compatibility level %d: %s", level, commentForLevel(level))
+// ensureDeprecationComment either replaces a stale "// Deprecated: ..." comment, or adds a new
+// one, so that both `go doc` and staticcheck SA1019 pick it up.
+func ensureDeprecationComment(node dst.Node, text string) bool {
+	return ensureCommentLine(node, "// Deprecated: ", text)
+}
+
+// ensureCommentLine ensures that the single-line comment identified by prefix reads exactly
+// full, replacing a stale one if it already exists, or inserting full as a new paragraph right
+// before the block of "+" tags otherwise. It operates on any decorated node (a *dst.GenDecl for
+// a type, or a *dst.Field for a field-level override).
+func ensureCommentLine(node dst.Node, prefix, full string) bool {
+	// copy of existing comments we can manipulate
+	comments := append([]string{}, node.Decorations().Start.All()...)
 
-	// if there is already a compatibility comment, replace if needed
+	// if the comment already exists, replace it if needed
 	for i, existingComment := range comments {
 		switch {
-		case existingComment == newComment:
+		case existingComment == full:
 			return false
-		case strings.HasPrefix(existingComment, "// Compatibility level "):
-			comments[i] = newComment
-			genDecl.Decorations().Start.Replace(comments...)
+		case strings.HasPrefix(existingComment, prefix):
+			comments[i] = full
+			node.Decorations().Start.Replace(comments...)
 			return true
 		}
 	}
 
-	// no existing compatibility comment, find a nice place to add one
+	// no existing comment, find a nice place to add one
 	insertIndex := len(comments)
 l:
 	for i := len(comments) - 1; i >= 0; i-- {
@@ -311,7 +702,7 @@ l:
 
 	// surround with empty ('//') comments if needed to ensure godoc paragraph breaks
 	newComments := []string{
-		newComment,
+		full,
 	}
 	switch {
 	case insertIndex == 0:
@@ -331,7 +722,7 @@ l:
 
 	// insert comments
 	comments = append(comments[:insertIndex], append(newComments, comments[insertIndex:]...)...)
-	genDecl.Decorations().Start.Replace(comments...)
+	node.Decorations().Start.Replace(comments...)
 
 	return true
 }
@@ -350,3 +741,64 @@ func commentForLevel(level int) string {
 		panic(level)
 	}
 }
+
+// deprecationCommentText builds the "// Deprecated: ..." line for kind, which is recognized by
+// both `go doc` and staticcheck SA1019.
+func deprecationCommentText(kind, since, removedIn, replacement string) string {
+	text := fmt.Sprintf("// Deprecated: %s is deprecated in %s; it will be removed in %s.", kind, since, removedIn)
+	if replacement != "" {
+		text += fmt.Sprintf(" Use %s instead.", replacement)
+	}
+	return text
+}
+
+// minReleasesNotice is the minimum number of minor releases of notice a deprecation must give,
+// per compatibility level: 3 for level 1, 2 for level 2, 1 for level 3 and none for level 4. Only
+// the level 1 value matches the 3 minor releases commentForLevel names; level 2 gets one fewer.
+func minReleasesNotice(level int) int {
+	switch level {
+	case 1:
+		return 3
+	case 2:
+		return 2
+	case 3:
+		return 1
+	default:
+		return 0 // level 4 (experimental/alpha) APIs may be removed immediately
+	}
+}
+
+// validateDeprecationLifecycle checks that removedIn comes after since, and that the gap between
+// them gives at least as much notice as minReleasesNotice requires for level. Versions that don't
+// parse as <major>[.<minor>] are left unchecked, since this package has no fixed opinion on what
+// an OpenShift release version looks like.
+func validateDeprecationLifecycle(apiTypeName string, level int, since, removedIn string) error {
+	sinceOrdinal, sinceOK := parseVersionOrdinal(since)
+	removedInOrdinal, removedInOK := parseVersionOrdinal(removedIn)
+	if !sinceOK || !removedInOK {
+		return nil
+	}
+	if removedInOrdinal <= sinceOrdinal {
+		return fmt.Errorf("%s: +%s (%s) must name a version later than +%s (%s)", apiTypeName, removedInTagName, removedIn, deprecatedTagName, since)
+	}
+	if notice := minReleasesNotice(level); removedInOrdinal-sinceOrdinal < notice {
+		return fmt.Errorf("%s: level %d APIs must give at least %d release(s) notice before removal, but %s is only removed %d release(s) after it was deprecated", apiTypeName, level, notice, apiTypeName, removedInOrdinal-sinceOrdinal)
+	}
+	return nil
+}
+
+// parseVersionOrdinal turns a "<major>[.<minor>]" version string into a single comparable int.
+func parseVersionOrdinal(version string) (int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 2)
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, false
+	}
+	minor := 0
+	if len(parts) == 2 {
+		if minor, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, false
+		}
+	}
+	return major*1000 + minor, true
+}
@@ -0,0 +1,109 @@
+package comment
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dave/dst/decorator"
+	"github.com/dave/dst/dstutil"
+)
+
+// runGenerator parses src as a single Go file and runs applyCompatibilityLevelComment over it,
+// returning the generator so callers can inspect g.err/g.findings/g.changed.
+func runGenerator(t *testing.T, src string) *compatibilityLevelCommentGenerator {
+	t.Helper()
+	file, err := decorator.Parse(src)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	g := &compatibilityLevelCommentGenerator{group: "example.openshift.io", version: "v1"}
+	dstutil.Apply(file, nil, g.applyCompatibilityLevelComment())
+	return g
+}
+
+func TestApplyFieldCompatibilityLevels_FieldStrongerThanType(t *testing.T) {
+	// The type itself must be validly tagged or g.fail trips on the type-level GA/beta/alpha
+	// check before the field-level check under test ever runs. A beta-versioned type may carry
+	// level 2 (beta rejects only levels 1 and 4), so package v1beta1 is used here rather than the
+	// GA-versioned v1 (which requires level 1).
+	src := `package v1beta1
+
+// +openshift:compatibility-gen:level=2
+type Foo struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+
+	// +openshift:compatibility-gen:level=1
+	Bar string ` + "`json:\"bar\"`" + `
+}
+`
+	g := runGenerator(t, src)
+	if g.err == nil {
+		t.Fatal("expected an error for a field offering a stronger guarantee than its type, got nil")
+	}
+	if !strings.Contains(g.err.Error(), "stronger compatibility guarantee") {
+		t.Errorf("unexpected error: %v", g.err)
+	}
+}
+
+func TestApplyFieldCompatibilityLevels_ValidOverrideIsCommented(t *testing.T) {
+	src := `package v1
+
+// +openshift:compatibility-gen:level=1
+type Foo struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+
+	// +openshift:compatibility-gen:level=4
+	Bar string ` + "`json:\"bar\"`" + `
+}
+`
+	g, out := runGeneratorSource(t, src)
+	if g.err != nil {
+		t.Fatalf("unexpected error: %v", g.err)
+	}
+	if !g.changed {
+		t.Fatal("expected the field comment to be inserted")
+	}
+	if !strings.Contains(out, "// Compatibility level 4: ") {
+		t.Errorf("rewritten source missing the level 4 field comment, got:\n%s", out)
+	}
+}
+
+func TestApplyFieldCompatibilityLevels_DeprecatedWithoutRemovedIn(t *testing.T) {
+	src := `package v1
+
+// +openshift:compatibility-gen:level=1
+type Foo struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+
+	// +openshift:compatibility-gen:level=1
+	// +openshift:compatibility-gen:deprecated=v1.0
+	Bar string ` + "`json:\"bar\"`" + `
+}
+`
+	g := runGenerator(t, src)
+	if g.err == nil {
+		t.Fatal("expected an error when a field is deprecated without a removed-in version")
+	}
+	if !strings.Contains(g.err.Error(), "must be specified together") {
+		t.Errorf("unexpected error: %v", g.err)
+	}
+}
+
+func TestApplyFieldCompatibilityLevels_UntaggedFieldLeftAlone(t *testing.T) {
+	src := `package v1
+
+// +openshift:compatibility-gen:level=1
+type Foo struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+
+	Bar string ` + "`json:\"bar\"`" + `
+}
+`
+	g, out := runGeneratorSource(t, src)
+	if g.err != nil {
+		t.Fatalf("unexpected error: %v", g.err)
+	}
+	if n := strings.Count(out, "// Compatibility level "); n != 1 {
+		t.Errorf("expected only the type's compatibility comment, found %d, got:\n%s", n, out)
+	}
+}
@@ -0,0 +1,187 @@
+package comment
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// crdCompatibilityAnnotation is added to a CRD (and to each of its versions) so that
+	// clients, docs generators, and admission tooling can read the compatibility level
+	// without parsing Go source.
+	crdCompatibilityAnnotation = "api.openshift.io/compatibility-level"
+	// openAPICompatibilityExtension mirrors the existing x-kubernetes-* extensions that
+	// OpenAPI consumers already know how to carry through generation.
+	openAPICompatibilityExtension = "x-openshift-compatibility-level"
+)
+
+// InjectCRDCompatibilityLevels walks the CRD YAML manifests in crdDir and, for every CRD whose
+// group and a version's kind matches an entry in levels, adds the
+// "api.openshift.io/compatibility-level" annotation to the CRD's metadata and to the matching
+// entries under spec.versions. Manifests that don't match anything in levels are left untouched.
+func InjectCRDCompatibilityLevels(levels map[GroupVersionKind]LevelInfo, crdDir string) error {
+	matches, err := filepath.Glob(filepath.Join(crdDir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	for _, crdFile := range matches {
+		raw, err := ioutil.ReadFile(crdFile)
+		if err != nil {
+			return err
+		}
+		var crd map[string]interface{}
+		if err := yaml.Unmarshal(raw, &crd); err != nil {
+			return fmt.Errorf("%s: %v", crdFile, err)
+		}
+
+		changed, err := injectCRDCompatibilityLevels(crd, levels)
+		if err != nil {
+			return fmt.Errorf("%s: %v", crdFile, err)
+		}
+		if !changed {
+			continue
+		}
+
+		out, err := yaml.Marshal(crd)
+		if err != nil {
+			return fmt.Errorf("%s: %v", crdFile, err)
+		}
+		if err := ioutil.WriteFile(crdFile, out, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// injectCRDCompatibilityLevels mutates crd in place and reports whether anything changed.
+func injectCRDCompatibilityLevels(crd map[string]interface{}, levels map[GroupVersionKind]LevelInfo) (bool, error) {
+	spec, ok := crd["spec"].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+	group, _ := spec["group"].(string)
+	names, _ := spec["names"].(map[string]interface{})
+	kind, _ := names["kind"].(string)
+	if group == "" || kind == "" {
+		return false, nil
+	}
+
+	versions, _ := spec["versions"].([]interface{})
+	var changed bool
+	var crdLevel *LevelInfo
+	for _, v := range versions {
+		version, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := version["name"].(string)
+		info, ok := levels[GroupVersionKind{Group: group, Version: name, Kind: kind}]
+		if !ok {
+			continue
+		}
+		setAnnotation(version, crdCompatibilityAnnotation, strconv.Itoa(info.Level))
+		changed = true
+		if storage, _ := version["storage"].(bool); storage || crdLevel == nil {
+			crdLevel = &info
+		}
+	}
+	if crdLevel != nil {
+		metadata, ok := crd["metadata"].(map[string]interface{})
+		if !ok {
+			metadata = map[string]interface{}{}
+			crd["metadata"] = metadata
+		}
+		setAnnotation(metadata, crdCompatibilityAnnotation, strconv.Itoa(crdLevel.Level))
+		changed = true
+	}
+	return changed, nil
+}
+
+// setAnnotation adds key/value to obj["annotations"], creating the map if needed.
+func setAnnotation(obj map[string]interface{}, key, value string) {
+	annotations, ok := obj["annotations"].(map[string]interface{})
+	if !ok {
+		annotations = map[string]interface{}{}
+		obj["annotations"] = annotations
+	}
+	annotations[key] = value
+}
+
+// InjectOpenAPICompatibilityExtensions rewrites the OpenAPI schema document at schemaPath,
+// adding an "x-openshift-compatibility-level" extension to every definition that corresponds to
+// an API type in levels. Definition names are expected to follow the generated OpenAPI
+// convention of ending in "<package>.<version>.<Kind>", where <package> is the Go import path
+// segment openapi-gen derives the definition name from (e.g. "route" for the "route.openshift.io"
+// group), not the full CRD group.
+func InjectOpenAPICompatibilityExtensions(levels map[GroupVersionKind]LevelInfo, schemaPath string) error {
+	raw, err := ioutil.ReadFile(schemaPath)
+	if err != nil {
+		return err
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("%s: %v", schemaPath, err)
+	}
+
+	definitions, ok := doc["definitions"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var changed bool
+	for name, def := range definitions {
+		definition, ok := def.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		gvk, ok := matchDefinitionName(name, levels)
+		if !ok {
+			continue
+		}
+		definition[openAPICompatibilityExtension] = levels[gvk].Level
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	// Unlike the CRD manifests above, the generated OpenAPI schema is JSON, not YAML; round
+	// it through encoding/json (as Report already does) so consumers that parse it strictly
+	// as JSON keep working.
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%s: %v", schemaPath, err)
+	}
+	return ioutil.WriteFile(schemaPath, out, 0644)
+}
+
+// matchDefinitionName finds the GroupVersionKind whose "<package>.<version>.<Kind>" suffix matches
+// the given OpenAPI definition name, where <package> is derived from gvk.Group the same way
+// openapi-gen derives it from the Go import path: the first label of the group, e.g. "route" for
+// "route.openshift.io". A full CRD group never appears in a real definition name, since definition
+// names come from Go package directories, not the domain-qualified API group.
+func matchDefinitionName(name string, levels map[GroupVersionKind]LevelInfo) (GroupVersionKind, bool) {
+	for gvk := range levels {
+		suffix := fmt.Sprintf("%s.%s.%s", groupPackageSegment(gvk.Group), gvk.Version, gvk.Kind)
+		if len(name) >= len(suffix) && name[len(name)-len(suffix):] == suffix {
+			return gvk, true
+		}
+	}
+	return GroupVersionKind{}, false
+}
+
+// groupPackageSegment returns the leading label of a CRD group, e.g. "route" for
+// "route.openshift.io", matching the Go package directory name openapi-gen derives OpenAPI
+// definition names from.
+func groupPackageSegment(group string) string {
+	if i := strings.IndexByte(group, '.'); i >= 0 {
+		return group[:i]
+	}
+	return group
+}
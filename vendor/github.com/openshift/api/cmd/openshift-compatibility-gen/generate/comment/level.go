@@ -0,0 +1,22 @@
+package comment
+
+// GroupVersionKind identifies the API type a compatibility level was discovered for. It mirrors
+// schema.GroupVersionKind but is kept local to this package so callers that only care about
+// compatibility metadata don't need to pull in apimachinery.
+type GroupVersionKind struct {
+	Group   string
+	Version string
+	Kind    string
+}
+
+// LevelInfo is the compatibility information discovered for a single API type by
+// GenerateCompatibilityCommentsWithLevels.
+type LevelInfo struct {
+	// Level is the compatibility level (1-4) as described by commentForLevel.
+	Level int
+	// Internal is true if the type is not expected to be exposed to a client; internal types
+	// are always treated as level 4.
+	Internal bool
+	// Deprecated is true if the type carries a +openshift:compatibility-gen:deprecated tag.
+	Deprecated bool
+}
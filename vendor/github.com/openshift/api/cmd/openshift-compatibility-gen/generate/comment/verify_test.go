@@ -0,0 +1,81 @@
+package comment
+
+import (
+	"testing"
+
+	"github.com/dave/dst/decorator"
+	"github.com/dave/dst/dstutil"
+)
+
+func TestVerifyOnlyCollectsEveryFindingInsteadOfHalting(t *testing.T) {
+	src := `package v1
+
+type Foo struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+}
+
+type Bar struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+}
+`
+	file, err := decorator.Parse(src)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	g := &compatibilityLevelCommentGenerator{group: "example.openshift.io", version: "v1", verifyOnly: true}
+	dstutil.Apply(file, nil, g.applyCompatibilityLevelComment())
+
+	if g.err != nil {
+		t.Fatalf("verify-only mode should never halt the walk, got err: %v", g.err)
+	}
+	if len(g.findings) != 2 {
+		t.Fatalf("expected a finding for both untagged types, got %d: %+v", len(g.findings), g.findings)
+	}
+	if g.findings[0].Kind != "Foo" || g.findings[1].Kind != "Bar" {
+		t.Errorf("unexpected findings: %+v", g.findings)
+	}
+}
+
+func TestVerifyOnlyDoesNotRewriteStaleComments(t *testing.T) {
+	src := `package v1
+
+// Compatibility level 4: no compatibility is provided
+// +openshift:compatibility-gen:level=1
+type Foo struct {
+	metav1.TypeMeta ` + "`json:\",inline\"`" + `
+}
+`
+	file, err := decorator.Parse(src)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	g := &compatibilityLevelCommentGenerator{group: "example.openshift.io", version: "v1", verifyOnly: true}
+	dstutil.Apply(file, nil, g.applyCompatibilityLevelComment())
+
+	if g.changed {
+		t.Error("verify-only mode must not mark the file as changed, it never rewrites comments")
+	}
+	if len(g.findings) != 1 {
+		t.Fatalf("expected one finding for the stale comment, got %d: %+v", len(g.findings), g.findings)
+	}
+}
+
+func TestBuildReportEntriesSortsByPackageThenKind(t *testing.T) {
+	levels := map[GroupVersionKind]LevelInfo{
+		{Group: "route.openshift.io", Version: "v1", Kind: "Route"}:           {Level: 1},
+		{Group: "apps.openshift.io", Version: "v1", Kind: "DeploymentConfig"}: {Level: 2, Deprecated: true},
+		{Group: "apps.openshift.io", Version: "v1", Kind: "Build"}:            {Level: 4, Internal: true},
+	}
+
+	entries := buildReportEntries(levels)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	want := []string{"apps.openshift.io/v1.Build", "apps.openshift.io/v1.DeploymentConfig", "route.openshift.io/v1.Route"}
+	for i, entry := range entries {
+		got := entry.Package + "." + entry.Kind
+		if got != want[i] {
+			t.Errorf("entry %d: got %q, want %q", i, got, want[i])
+		}
+	}
+}